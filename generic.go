@@ -0,0 +1,567 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCanceled is the error observed through Await/Then/Catch once a promise
+// has been explicitly Canceled. It is distinct from a context's ctx.Err():
+// a context cancellation rejects a promise with ctx.Err() (an ordinary
+// rejection a Catch can inspect), while Cancel transitions a pending
+// promise to a canceled state that IsCanceled can observe separately.
+var ErrCanceled = errors.New("promise: canceled")
+
+// TypedPromise[T] is the generic counterpart of the untyped Promise. It carries
+// its eventual value as a concrete type T instead of interface{}, so callers
+// get compile-time type safety instead of a runtime type assertion on every
+// callback. The untyped Promise is now implemented as a thin wrapper around
+// TypedPromise[interface{}] - see promise.go.
+//
+// Go methods cannot introduce additional type parameters, so operations
+// that change the value's type - Then and Catch - are free functions
+// instead of methods.
+//
+// The free-function constructors below (AsyncG, ResolveG, RejectG, AwaitG,
+// EachG, AllG, RaceG, AllSettledG) carry a "G" suffix because Go does not
+// allow a generic function to share a name with the pre-existing untyped
+// one regardless of type-parameter arity.
+type TypedPromise[T any] struct {
+	pending  bool
+	canceled bool
+
+	// See the untyped Promise's executor field for the full description
+	// of this contract.
+	executor func(resolve func(T), reject func(interface{}))
+
+	// Stores the result passed to Resolve()
+	result T
+
+	// Stores the error passed to reject()
+	err error
+
+	// Mutex protects against data race conditions.
+	mutex sync.Mutex
+
+	elapseTime time.Duration
+
+	calTime bool
+
+	// done is closed exactly once, when the promise settles (resolved,
+	// rejected or canceled). Waiters select on it instead of blocking on a
+	// WaitGroup so a wait can be preempted by a context deadline.
+	done chan struct{}
+}
+
+func (promise *TypedPromise[T]) CalTime() *TypedPromise[T] {
+	promise.calTime = true
+	return promise
+}
+
+func (promise *TypedPromise[T]) Elapse() time.Duration {
+	return promise.elapseTime
+}
+
+func (promise *TypedPromise[T]) isPending() bool {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+	return promise.pending
+}
+
+// IsCanceled reports whether the promise was transitioned to the canceled
+// state via Cancel, as opposed to an ordinary rejection.
+func (promise *TypedPromise[T]) IsCanceled() bool {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+	return promise.canceled
+}
+
+// peek reports whether the promise has already settled and, if so, its
+// result and error. It never blocks, which lets the combinators below take
+// a synchronous fast path for inputs that are already done instead of
+// always going through Async/Then/Catch channel plumbing.
+func (promise *TypedPromise[T]) peek() (settled bool, result T, err error) {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+	if promise.pending {
+		return false, result, nil
+	}
+	return true, promise.result, promise.err
+}
+
+// Cancel transitions a pending promise to a canceled state: Await and the
+// combinators observe it as a rejection with ErrCanceled, but IsCanceled
+// distinguishes it from a rejection raised by the executor itself. Cancel
+// is a no-op once the promise has already settled.
+func (promise *TypedPromise[T]) Cancel() {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+
+	if !promise.pending {
+		return
+	}
+	promise.canceled = true
+	promise.err = ErrCanceled
+	promise.pending = false
+
+	close(promise.done)
+}
+
+// AsyncG instantiates and returns a pointer to a new TypedPromise[T].
+func AsyncG[T any](executor func(resolve func(T), reject func(interface{}))) *TypedPromise[T] {
+	var promise = &TypedPromise[T]{
+		pending:  true,
+		executor: executor,
+		done:     make(chan struct{}),
+	}
+
+	go func() {
+		defer promise.handlePanic()
+		promise.executor(promise.Resolve, promise.Reject)
+	}()
+
+	return promise
+}
+
+func (promise *TypedPromise[T]) Resolve(resolution T) {
+	promise.mutex.Lock()
+
+	if !promise.pending {
+		promise.mutex.Unlock()
+		return
+	}
+
+	promise.result = resolution
+	promise.pending = false
+
+	close(promise.done)
+	promise.mutex.Unlock()
+}
+
+func (promise *TypedPromise[T]) Reject(err interface{}) {
+	promise.mutex.Lock()
+	defer promise.mutex.Unlock()
+
+	if !promise.pending {
+		return
+	}
+	if err1, ok := err.(error); ok {
+		promise.err = err1
+	} else {
+		promise.err = errors.New(err.(string))
+	}
+	promise.pending = false
+
+	close(promise.done)
+}
+
+func (promise *TypedPromise[T]) handlePanic() {
+	var r = recover()
+	if r != nil {
+		if err, ok := r.(error); ok {
+			// Wrap rather than re-stringify, so errors.Is/As still see
+			// through to the original error recovered from the panic.
+			promise.Reject(fmt.Errorf("%w", err))
+		} else {
+			promise.Reject(errors.New(r.(string)))
+		}
+	}
+}
+
+// Then appends a fulfillment handler to p and returns a new TypedPromise[U]
+// resolving to the value returned by fn, or rejecting with the error it
+// returns. Go methods cannot introduce a second type parameter, so Then is
+// a free function rather than a method.
+func Then[T, U any](p *TypedPromise[T], fn func(data T) (U, error)) *TypedPromise[U] {
+	return AsyncG(func(resolve func(U), reject func(interface{})) {
+		result, err := p.Await()
+		if err != nil {
+			reject(err)
+			return
+		}
+		next, err := fn(result)
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(next)
+	})
+}
+
+// Catch appends a rejection handler to p and returns a new TypedPromise[T]. If p
+// is rejected, the new promise is rejected with rejection(err); if p is
+// fulfilled, the new promise resolves to the same value.
+func Catch[T any](p *TypedPromise[T], rejection func(err error) interface{}) *TypedPromise[T] {
+	return AsyncG(func(resolve func(T), reject func(interface{})) {
+		result, err := p.Await()
+		if err != nil {
+			reject(rejection(err))
+			return
+		}
+		resolve(result)
+	})
+}
+
+// Await is a blocking function that waits for all callbacks to be executed.
+// Returns value and error. Call on an already resolved Promise to get its
+// result and error.
+func (promise *TypedPromise[T]) Await() (T, error) {
+	if promise.calTime {
+		start := time.Now()
+		<-promise.done
+		promise.elapseTime = time.Now().Sub(start)
+		return promise.result, promise.err
+	}
+	<-promise.done
+	return promise.result, promise.err
+}
+
+// AwaitG mirrors the untyped Await free function for TypedPromise[T].
+func AwaitG[T any](p *TypedPromise[T]) (T, error) {
+	return p.Await()
+}
+
+// AwaitCtxG waits for p to settle, or for ctx to be done, whichever comes
+// first. A canceled ctx only preempts this particular wait - it does not
+// Cancel p, so other waiters still observe p's eventual outcome.
+func AwaitCtxG[T any](ctx context.Context, p *TypedPromise[T]) (T, error) {
+	select {
+	case <-p.done:
+		return p.result, p.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (promise *TypedPromise[T]) AsCallback(f func(T, error)) {
+	go func() {
+		<-promise.done
+		f(promise.result, promise.err)
+	}()
+}
+
+// AsyncCtxG is like AsyncG, but the executor additionally receives ctx, and
+// the returned promise is rejected with ctx.Err() if ctx is done before the
+// executor settles it itself.
+func AsyncCtxG[T any](ctx context.Context, executor func(ctx context.Context, resolve func(T), reject func(interface{}))) *TypedPromise[T] {
+	promise := AsyncG(func(resolve func(T), reject func(interface{})) {
+		executor(ctx, resolve, reject)
+	})
+	watchCtx(ctx, promise)
+	return promise
+}
+
+// watchCtx rejects promise with ctx.Err() if ctx is done before promise
+// settles on its own.
+func watchCtx[T any](ctx context.Context, promise *TypedPromise[T]) {
+	if ctx == nil || ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			promise.Reject(ctx.Err())
+		case <-promise.done:
+		}
+	}()
+}
+
+type resolutionHelper[T any] struct {
+	index int
+	data  T
+}
+
+// EachG waits on promises in order, like AllG but sequentially rather than
+// concurrently. If every promise up to the first rejection is already
+// settled, it builds its result synchronously with ResolveG/RejectG instead
+// of starting a goroutine that Awaits each input.
+func EachG[T any](promises ...*TypedPromise[T]) *TypedPromise[[]T] {
+	resolutions := make([]T, 0, len(promises))
+	for _, p := range promises {
+		settled, result, err := p.peek()
+		if !settled {
+			return asyncEach(resolutions, promises[len(resolutions):])
+		}
+		if err != nil {
+			return RejectG[[]T](err)
+		}
+		resolutions = append(resolutions, result)
+	}
+	return ResolveG(resolutions)
+}
+
+// asyncEach finishes EachG the slow way once a pending promise is found:
+// done holds the results already collected synchronously, and remaining
+// is the (possibly still-pending) tail still to Await.
+func asyncEach[T any](done []T, remaining []*TypedPromise[T]) *TypedPromise[[]T] {
+	return AsyncG(func(resolve func([]T), reject func(interface{})) {
+		resolutions := append([]T{}, done...)
+		for _, p := range remaining {
+			result, err := p.Await()
+			if err != nil {
+				reject(err)
+				return
+			}
+			resolutions = append(resolutions, result)
+		}
+		resolve(resolutions)
+	})
+}
+
+// allFastPath probes promises without blocking. It is ready (and the slow
+// path can be skipped) as soon as it finds a rejection, or once every
+// promise has been observed fulfilled.
+func allFastPath[T any](promises []*TypedPromise[T]) (results []T, err error, ready bool) {
+	results = make([]T, len(promises))
+	allFulfilled := true
+	for i, p := range promises {
+		settled, result, rejErr := p.peek()
+		if settled && rejErr != nil {
+			return nil, rejErr, true
+		}
+		if !settled {
+			allFulfilled = false
+			continue
+		}
+		results[i] = result
+	}
+	return results, nil, allFulfilled
+}
+
+// AllG waits for all promises to be resolved, or for any to be rejected.
+// If the returned promise resolves, it is resolved with an aggregating
+// slice of the values from the resolved promises in the same order as
+// defined in promises. If it rejects, it is rejected with the reason from
+// the first promise in promises that was rejected.
+func AllG[T any](promises ...*TypedPromise[T]) *TypedPromise[[]T] {
+	psLen := len(promises)
+	if psLen == 0 {
+		return ResolveG[[]T](make([]T, 0))
+	}
+
+	if results, err, ready := allFastPath(promises); ready {
+		if err != nil {
+			return RejectG[[]T](err)
+		}
+		return ResolveG(results)
+	}
+
+	return AsyncG(func(resolve func([]T), reject func(interface{})) {
+		resolutionsChan := make(chan resolutionHelper[T], psLen)
+		errorChan := make(chan error, psLen)
+
+		for index, p := range promises {
+			func(i int, p *TypedPromise[T]) {
+				chained := Then(p, func(data T) (T, error) {
+					resolutionsChan <- resolutionHelper[T]{i, data}
+					return data, nil
+				})
+				Catch(chained, func(err error) interface{} {
+					errorChan <- err
+					return err
+				})
+			}(index, p)
+		}
+
+		resolutions := make([]T, psLen)
+		for x := 0; x < psLen; x++ {
+			select {
+			case resolution := <-resolutionsChan:
+				resolutions[resolution.index] = resolution.data
+
+			case err := <-errorChan:
+				reject(err)
+				return
+			}
+		}
+		resolve(resolutions)
+	})
+}
+
+// RaceG waits until any of the promises is resolved or rejected. If the
+// returned promise resolves, it is resolved with the value of the first
+// promise in promises that resolved. If it rejects, it is rejected with
+// the reason from the first promise that was rejected.
+func RaceG[T any](promises ...*TypedPromise[T]) *TypedPromise[T] {
+	psLen := len(promises)
+	if psLen == 0 {
+		var zero T
+		return ResolveG(zero)
+	}
+
+	for _, p := range promises {
+		if settled, result, err := p.peek(); settled {
+			if err != nil {
+				return RejectG[T](err)
+			}
+			return ResolveG(result)
+		}
+	}
+
+	return AsyncG(func(resolve func(T), reject func(interface{})) {
+		resolutionsChan := make(chan T, psLen)
+		errorChan := make(chan error, psLen)
+
+		for _, p := range promises {
+			chained := Then(p, func(data T) (T, error) {
+				resolutionsChan <- data
+				return data, nil
+			})
+			Catch(chained, func(err error) interface{} {
+				errorChan <- err
+				return err
+			})
+		}
+
+		select {
+		case resolution := <-resolutionsChan:
+			resolve(resolution)
+
+		case err := <-errorChan:
+			reject(err)
+		}
+	})
+}
+
+// Settlement describes the outcome of a single promise passed to
+// AllSettledG: either Fulfilled is true and Value holds the result, or
+// Fulfilled is false and Err holds the rejection reason.
+type Settlement[T any] struct {
+	Value     T
+	Err       error
+	Fulfilled bool
+}
+
+// allSettledFastPath probes promises without blocking, and is ready once
+// every one of them has already settled.
+func allSettledFastPath[T any](promises []*TypedPromise[T]) (settlements []Settlement[T], ready bool) {
+	settlements = make([]Settlement[T], len(promises))
+	allDone := true
+	for i, p := range promises {
+		settled, result, err := p.peek()
+		if !settled {
+			allDone = false
+			continue
+		}
+		if err != nil {
+			settlements[i] = Settlement[T]{Err: err}
+		} else {
+			settlements[i] = Settlement[T]{Value: result, Fulfilled: true}
+		}
+	}
+	return settlements, allDone
+}
+
+// AllSettledG waits until all promises have settled (each may resolve or
+// reject). It returns a promise that resolves, once every given promise has
+// either resolved or rejected, to a slice describing the outcome of each.
+func AllSettledG[T any](promises ...*TypedPromise[T]) *TypedPromise[[]Settlement[T]] {
+	psLen := len(promises)
+	if psLen == 0 {
+		return ResolveG[[]Settlement[T]](nil)
+	}
+
+	if settlements, ready := allSettledFastPath(promises); ready {
+		return ResolveG(settlements)
+	}
+
+	return AsyncG(func(resolve func([]Settlement[T]), reject func(interface{})) {
+		resolutionsChan := make(chan resolutionHelper[Settlement[T]], psLen)
+
+		for index, p := range promises {
+			func(i int, p *TypedPromise[T]) {
+				chained := Then(p, func(data T) (T, error) {
+					resolutionsChan <- resolutionHelper[Settlement[T]]{i, Settlement[T]{Value: data, Fulfilled: true}}
+					return data, nil
+				})
+				Catch(chained, func(err error) interface{} {
+					resolutionsChan <- resolutionHelper[Settlement[T]]{i, Settlement[T]{Err: err}}
+					return err
+				})
+			}(index, p)
+		}
+
+		resolutions := make([]Settlement[T], psLen)
+		for x := 0; x < psLen; x++ {
+			resolution := <-resolutionsChan
+			resolutions[resolution.index] = resolution.data
+		}
+		resolve(resolutions)
+	})
+}
+
+// ResolveG returns a TypedPromise[T] that has been resolved with a given value.
+func ResolveG[T any](resolution T) *TypedPromise[T] {
+	return AsyncG(func(resolve func(T), reject func(interface{})) {
+		resolve(resolution)
+	})
+}
+
+// RejectG returns a TypedPromise[T] that has been rejected with a given error.
+func RejectG[T any](err error) *TypedPromise[T] {
+	return AsyncG(func(resolve func(T), reject func(interface{})) {
+		reject(err)
+	})
+}
+
+// guardCtx races result against ctx. If ctx is already done, it
+// short-circuits synchronously instead of waiting on result at all.
+// Otherwise, if ctx is done before result settles, it rejects with
+// ctx.Err() without touching result or its inputs - the inputs are
+// caller-supplied and may be shared with unrelated callers, so guardCtx
+// only ever stops *waiting* on them, the same way AwaitCtxG does; it never
+// Cancels a promise it doesn't own.
+func guardCtx[T, U any](ctx context.Context, result *TypedPromise[U], inputs []*TypedPromise[T]) *TypedPromise[U] {
+	if ctx == nil || ctx.Done() == nil {
+		return result
+	}
+
+	if err := ctx.Err(); err != nil {
+		return RejectG[U](err)
+	}
+
+	return AsyncG(func(resolve func(U), reject func(interface{})) {
+		select {
+		case <-result.done:
+			if result.err != nil {
+				reject(result.err)
+				return
+			}
+			resolve(result.result)
+		case <-ctx.Done():
+			reject(ctx.Err())
+		}
+	})
+}
+
+// EachCtxG is like EachG, but rejects with ctx.Err() if ctx is done before
+// every promise has resolved.
+func EachCtxG[T any](ctx context.Context, promises ...*TypedPromise[T]) *TypedPromise[[]T] {
+	return guardCtx(ctx, EachG(promises...), promises)
+}
+
+// AllCtxG is like AllG, but rejects with ctx.Err() if ctx is done before
+// every promise has settled. The promises still pending are left untouched
+// - they belong to the caller, and other holders may still be waiting on
+// them.
+func AllCtxG[T any](ctx context.Context, promises ...*TypedPromise[T]) *TypedPromise[[]T] {
+	return guardCtx(ctx, AllG(promises...), promises)
+}
+
+// RaceCtxG is like RaceG, but rejects with ctx.Err() if ctx is done before
+// any promise has settled. The promises still pending are left untouched
+// - they belong to the caller, and other holders may still be waiting on
+// them.
+func RaceCtxG[T any](ctx context.Context, promises ...*TypedPromise[T]) *TypedPromise[T] {
+	return guardCtx(ctx, RaceG(promises...), promises)
+}
+
+// AllSettledCtxG is like AllSettledG, but rejects with ctx.Err() if ctx is
+// done before every promise has settled.
+func AllSettledCtxG[T any](ctx context.Context, promises ...*TypedPromise[T]) *TypedPromise[[]Settlement[T]] {
+	return guardCtx(ctx, AllSettledG(promises...), promises)
+}
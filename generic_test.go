@@ -0,0 +1,254 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAsyncGResolve(t *testing.T) {
+	p := AsyncG(func(resolve func(int), reject func(interface{})) {
+		resolve(7)
+	})
+
+	result, err := p.Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 7 {
+		t.Fatalf("got %d, want 7", result)
+	}
+}
+
+func TestAsyncGReject(t *testing.T) {
+	want := errors.New("boom")
+	p := AsyncG(func(resolve func(int), reject func(interface{})) {
+		reject(want)
+	})
+
+	if _, err := p.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestAsyncGPanicIsRejection(t *testing.T) {
+	want := errors.New("kaboom")
+	p := AsyncG(func(resolve func(int), reject func(interface{})) {
+		panic(want)
+	})
+
+	if _, err := p.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want an error wrapping %v", err, want)
+	}
+}
+
+func TestThenChains(t *testing.T) {
+	p := ResolveG(2)
+	doubled := Then(p, func(data int) (int, error) {
+		return data * 2, nil
+	})
+
+	result, err := doubled.Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 4 {
+		t.Fatalf("got %d, want 4", result)
+	}
+}
+
+func TestThenPropagatesRejection(t *testing.T) {
+	want := errors.New("upstream failure")
+	p := RejectG[int](want)
+	chained := Then(p, func(data int) (int, error) {
+		t.Fatal("fulfillment handler should not run after a rejection")
+		return data, nil
+	})
+
+	if _, err := chained.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestCatchTransformsRejectionReason(t *testing.T) {
+	want := errors.New("translated")
+	p := RejectG[int](errors.New("original"))
+	translated := Catch(p, func(err error) interface{} {
+		return want
+	})
+
+	if _, err := translated.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestCatchPassesThroughFulfillment(t *testing.T) {
+	p := ResolveG(5)
+	untouched := Catch(p, func(err error) interface{} {
+		t.Fatal("rejection handler should not run for a fulfilled promise")
+		return err
+	})
+
+	result, err := untouched.Await()
+	if err != nil || result != 5 {
+		t.Fatalf("got %d, %v, want 5, nil", result, err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	p := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(50 * time.Millisecond)
+		resolve(1)
+	})
+	p.Cancel()
+
+	if _, err := p.Await(); !errors.Is(err, ErrCanceled) {
+		t.Fatalf("got %v, want %v", err, ErrCanceled)
+	}
+	if !p.IsCanceled() {
+		t.Fatal("IsCanceled should report true after Cancel")
+	}
+}
+
+func TestCancelNoopAfterSettle(t *testing.T) {
+	p := ResolveG(5)
+	p.Await()
+	p.Cancel()
+
+	result, err := p.Await()
+	if err != nil || result != 5 {
+		t.Fatalf("Cancel after settle should be a no-op, got %d, %v", result, err)
+	}
+	if p.IsCanceled() {
+		t.Fatal("IsCanceled should stay false once a promise already settled")
+	}
+}
+
+func TestEachGPreservesOrder(t *testing.T) {
+	p1 := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(20 * time.Millisecond)
+		resolve(1)
+	})
+	p2 := ResolveG(2)
+	p3 := ResolveG(3)
+
+	result, err := EachG(p1, p2, p3).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int{1, 2, 3}
+	for i, v := range want {
+		if result[i] != v {
+			t.Fatalf("got %v, want %v", result, want)
+		}
+	}
+}
+
+func TestAllGAggregatesInOrder(t *testing.T) {
+	p1 := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(20 * time.Millisecond)
+		resolve(1)
+	})
+	p2 := ResolveG(2)
+
+	result, err := AllG(p1, p2).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0] != 1 || result[1] != 2 {
+		t.Fatalf("got %v, want [1 2]", result)
+	}
+}
+
+func TestAllGRejectsOnFirstError(t *testing.T) {
+	want := errors.New("failed")
+	p1 := RejectG[int](want)
+	p2 := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(20 * time.Millisecond)
+		resolve(2)
+	})
+
+	if _, err := AllG(p1, p2).Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestRaceGReturnsFirstSettled(t *testing.T) {
+	fast := ResolveG(1)
+	slow := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(50 * time.Millisecond)
+		resolve(2)
+	})
+
+	result, err := RaceG(fast, slow).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != 1 {
+		t.Fatalf("got %d, want 1", result)
+	}
+}
+
+func TestAllSettledGReportsEachOutcome(t *testing.T) {
+	ok := ResolveG(1)
+	want := errors.New("bad")
+	fail := RejectG[int](want)
+
+	settlements, err := AllSettledG(ok, fail).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !settlements[0].Fulfilled || settlements[0].Value != 1 {
+		t.Fatalf("got %+v, want fulfilled with 1", settlements[0])
+	}
+	if settlements[1].Fulfilled || !errors.Is(settlements[1].Err, want) {
+		t.Fatalf("got %+v, want rejected with %v", settlements[1], want)
+	}
+}
+
+func TestAwaitCtxGTimesOutWithoutCancelingPromise(t *testing.T) {
+	p := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(100 * time.Millisecond)
+		resolve(1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := AwaitCtxG(ctx, p); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	result, err := p.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("promise should still settle on its own, got %d, %v", result, err)
+	}
+	if p.IsCanceled() {
+		t.Fatal("AwaitCtxG must not Cancel the promise it was waiting on")
+	}
+}
+
+func TestAllCtxGRejectsWithoutCancelingInputs(t *testing.T) {
+	slow := AsyncG(func(resolve func(int), reject func(interface{})) {
+		time.Sleep(100 * time.Millisecond)
+		resolve(1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := AllCtxG(ctx, slow).Await(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// slow belongs to the caller; AllCtxG must leave it running rather than
+	// Canceling it out from under any other holder.
+	result, err := slow.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("input promise should still settle on its own, got %d, %v", result, err)
+	}
+	if slow.IsCanceled() {
+		t.Fatal("AllCtxG must not Cancel promises it doesn't own")
+	}
+}
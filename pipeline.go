@@ -0,0 +1,182 @@
+package promise
+
+import (
+	"errors"
+	"sync"
+)
+
+// embargoCapacity bounds how many calls a pipeEmbargo will buffer while its
+// parent is still pending. It exists so a runaway producer piping against a
+// promise that never settles can't grow the queue without limit.
+const embargoCapacity = 1024
+
+// errEmbargoFull is the rejection reason used when a pipeEmbargo is already
+// at embargoCapacity.
+var errEmbargoFull = errors.New("promise: pipe embargo queue is full")
+
+// Resolver exposes external control over a Promise created by NewPromise,
+// so the promise can be settled by forwarding another promise's outcome
+// (see Forward) instead of being driven by an Async executor.
+type Resolver struct {
+	promise *Promise
+}
+
+// Fulfill resolves the Resolver's promise with value.
+func (r *Resolver) Fulfill(value interface{}) {
+	r.promise.Resolve(value)
+}
+
+// Reject rejects the Resolver's promise with err.
+func (r *Resolver) Reject(err interface{}) {
+	r.promise.Reject(err)
+}
+
+// Forward arranges for r to Fulfill or Reject once p settles, saving the
+// caller from spawning its own goroutine to Await p first. It registers
+// directly against p's embargo (see pipeEmbargo below) rather than calling
+// p.AsCallback, so it doesn't add its own dedicated goroutine on top of the
+// one already doing the waiting.
+func (r *Resolver) Forward(p *Promise) {
+	ok := p.onSettle(func(value interface{}, err error) {
+		if err != nil {
+			r.Reject(err)
+			return
+		}
+		r.Fulfill(value)
+	})
+	if !ok {
+		r.Reject(errEmbargoFull)
+	}
+}
+
+// NewPromise returns a pending Promise together with a Resolver that can
+// Fulfill or Reject it from outside the usual Async executor.
+func NewPromise() (*Promise, *Resolver) {
+	p := &Promise{inner: &TypedPromise[interface{}]{pending: true, done: make(chan struct{})}}
+	return p, &Resolver{promise: p}
+}
+
+// pipeEmbargo is the bounded FIFO queue backing Promise.Pipe, modeled on
+// Cap'n Proto's EmbargoClient: while the parent promise is still pending,
+// queued calls buffer here (up to embargoCapacity); a flusher goroutine
+// waits for the parent to settle and then drains them strictly in
+// submission order, so causality between chained calls is preserved. The
+// flusher only runs while there is work to do: enqueue starts one on demand
+// and it exits as soon as the queue runs dry, instead of parking forever,
+// so a Promise that is piped once doesn't leak a goroutine for the rest of
+// its life. A later enqueue on an idle embargo simply starts a fresh
+// flusher, whose wait on the parent returns immediately if the parent has
+// already settled.
+type pipeEmbargo struct {
+	parent *Promise
+
+	mu     sync.Mutex
+	jobs   []func()
+	active bool
+}
+
+func newPipeEmbargo(parent *Promise) *pipeEmbargo {
+	return &pipeEmbargo{parent: parent}
+}
+
+// enqueue buffers job for the flusher to run and reports whether it fit
+// within embargoCapacity; the caller is responsible for settling its own
+// promise with errEmbargoFull when it doesn't.
+func (e *pipeEmbargo) enqueue(job func()) bool {
+	e.mu.Lock()
+	if len(e.jobs) >= embargoCapacity {
+		e.mu.Unlock()
+		return false
+	}
+	e.jobs = append(e.jobs, job)
+	start := !e.active
+	e.active = true
+	e.mu.Unlock()
+
+	if start {
+		go e.flush()
+	}
+	return true
+}
+
+func (e *pipeEmbargo) flush() {
+	e.parent.Await()
+
+	for {
+		e.mu.Lock()
+		if len(e.jobs) == 0 {
+			e.active = false
+			e.mu.Unlock()
+			return
+		}
+		job := e.jobs[0]
+		e.jobs = e.jobs[1:]
+		e.mu.Unlock()
+
+		job()
+	}
+}
+
+// onSettle arranges for job to run, exactly once, with promise's eventual
+// value and error, once promise settles, and reports whether it was
+// accepted into promise's embargo (see embargoCapacity). It shares
+// promise's pipeEmbargo with Pipe, so a call made while the embargo already
+// has jobs queued reuses the running flusher rather than spawning its own
+// dedicated goroutine; on an idle embargo it starts the one on-demand,
+// self-terminating flusher.
+func (promise *Promise) onSettle(job func(value interface{}, err error)) bool {
+	promise.embargoOnce.Do(func() {
+		promise.embargo = newPipeEmbargo(promise)
+	})
+
+	return promise.embargo.enqueue(func() {
+		value, err := promise.Await()
+		job(value, err)
+	})
+}
+
+// Pipe lets a caller chain an operation on promise's eventual value and get
+// back a new Promise immediately, without waiting for promise to settle
+// first: fn is called with promise's resolved value once it is available,
+// and the returned Promise is flattened into Pipe's result. If promise (or
+// the promise fn returns) rejects, Pipe's result rejects with the same
+// reason and fn's downstream call never runs past that point.
+func (promise *Promise) Pipe(fn func(value interface{}) *Promise) *Promise {
+	promise.embargoOnce.Do(func() {
+		promise.embargo = newPipeEmbargo(promise)
+	})
+
+	out, resolver := NewPromise()
+
+	ok := promise.embargo.enqueue(func() {
+		// fn is caller code running on the shared flusher goroutine; a panic
+		// here must reject out instead of taking down the flusher (and every
+		// promise still queued behind it), matching how AsyncG's handlePanic
+		// turns a panicking executor into a rejection.
+		defer func() {
+			if r := recover(); r != nil {
+				resolver.Reject(r)
+			}
+		}()
+
+		value, err := promise.Await()
+		if err != nil {
+			resolver.Reject(err)
+			return
+		}
+
+		next := fn(value)
+		result, err := next.Await()
+		if err != nil {
+			resolver.Reject(err)
+			return
+		}
+
+		resolver.Fulfill(result)
+	})
+	if !ok {
+		resolver.Reject(errEmbargoFull)
+	}
+
+	return out
+}
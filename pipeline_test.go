@@ -0,0 +1,180 @@
+package promise
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewPromiseResolverFulfill(t *testing.T) {
+	p, resolver := NewPromise()
+	resolver.Fulfill(1)
+
+	result, err := p.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("got %v, %v, want 1, nil", result, err)
+	}
+}
+
+func TestNewPromiseResolverReject(t *testing.T) {
+	p, resolver := NewPromise()
+	want := errors.New("boom")
+	resolver.Reject(want)
+
+	if _, err := p.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestResolverForwardFulfills(t *testing.T) {
+	src := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(10 * time.Millisecond)
+		resolve(42)
+	})
+	out, resolver := NewPromise()
+	resolver.Forward(src)
+
+	result, err := out.Await()
+	if err != nil || result != 42 {
+		t.Fatalf("got %v, %v, want 42, nil", result, err)
+	}
+}
+
+func TestResolverForwardRejects(t *testing.T) {
+	want := errors.New("upstream failed")
+	src := Reject(want)
+	out, resolver := NewPromise()
+	resolver.Forward(src)
+
+	if _, err := out.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPipeWaitsThenFlattensResult(t *testing.T) {
+	p := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(10 * time.Millisecond)
+		resolve(1)
+	})
+
+	out := p.Pipe(func(value interface{}) *Promise {
+		return Resolve(value.(int) + 1)
+	})
+
+	result, err := out.Await()
+	if err != nil || result != 2 {
+		t.Fatalf("got %v, %v, want 2, nil", result, err)
+	}
+}
+
+func TestPipePropagatesParentRejection(t *testing.T) {
+	want := errors.New("parent failed")
+	p := Reject(want)
+
+	out := p.Pipe(func(value interface{}) *Promise {
+		t.Fatal("fn should not run when the parent rejects")
+		return Resolve(value)
+	})
+
+	if _, err := out.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPipePropagatesDownstreamRejection(t *testing.T) {
+	want := errors.New("downstream failed")
+	p := Resolve(1)
+
+	out := p.Pipe(func(value interface{}) *Promise {
+		return Reject(want)
+	})
+
+	if _, err := out.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPipePreservesSubmissionOrder(t *testing.T) {
+	blocker, resolver := NewPromise()
+
+	var mu sync.Mutex
+	var order []int
+	var outs []*Promise
+
+	// fn runs synchronously on the shared flusher goroutine in submission
+	// order, so recording there - rather than in a separate goroutine per
+	// Await - is what actually observes flush ordering.
+	for i := 0; i < 20; i++ {
+		i := i
+		outs = append(outs, blocker.Pipe(func(value interface{}) *Promise {
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			return Resolve(i)
+		}))
+	}
+
+	resolver.Fulfill(nil)
+	for _, out := range outs {
+		if _, err := out.Await(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for i, v := range order {
+		if v != i {
+			t.Fatalf("jobs ran out of submission order: %v", order)
+		}
+	}
+}
+
+func TestPipePanicRejectsInsteadOfCrashing(t *testing.T) {
+	p := Resolve(1)
+	out := p.Pipe(func(value interface{}) *Promise {
+		panic("boom")
+	})
+
+	if _, err := out.Await(); err == nil {
+		t.Fatal("expected a rejection after fn panics, got nil error")
+	}
+}
+
+func TestPipeEmbargoRejectsOnceFull(t *testing.T) {
+	blocker, resolver := NewPromise()
+
+	var last *Promise
+	for i := 0; i < embargoCapacity+1; i++ {
+		last = blocker.Pipe(func(value interface{}) *Promise {
+			return Resolve(value)
+		})
+	}
+	resolver.Fulfill(1)
+
+	if _, err := last.Await(); !errors.Is(err, errEmbargoFull) {
+		t.Fatalf("got %v, want %v", err, errEmbargoFull)
+	}
+}
+
+func TestPipeFlusherDoesNotLeakGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 50; i++ {
+		out := Resolve(i).Pipe(func(value interface{}) *Promise {
+			return Resolve(value)
+		})
+		if _, err := out.Await(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if runtime.NumGoroutine() <= before+2 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("goroutines leaked: before=%d after=%d", before, runtime.NumGoroutine())
+}
@@ -1,7 +1,7 @@
 package promise
 
 import (
-	"errors"
+	"context"
 	"sync"
 	"time"
 )
@@ -12,35 +12,17 @@ import (
 // This lets asynchronous methods return values like synchronous methods:
 // instead of immediately returning the final value, the asynchronous method
 // returns a promise to supply the value at some point in the future.
+//
+// Promise is kept for backward compatibility and is now a thin wrapper
+// around the generic TypedPromise[interface{}]; see TypedPromise[T] for the
+// type-safe counterpart that should be preferred in new code.
 type Promise struct {
-	pending bool
+	inner *TypedPromise[interface{}]
 
-	// A function that is passed with the arguments Resolve and reject.
-	// The executor function is executed immediately by the Promise implementation,
-	// passing Resolve and reject functions (the executor is called
-	// before the Promise constructor even returns the created object).
-	// The Resolve and reject functions, when called, Resolve or reject
-	// the promise, respectively. The executor normally initiates some
-	// asynchronous work, and then, once that completes, either calls the
-	// Resolve function to Resolve the promise or else rejects it if
-	// an error or panic occurred.
-	executor func(resolve func(interface{}), reject func(interface{}))
-
-	// Stores the result passed to Resolve()
-	result interface{}
-
-	// Stores the error passed to reject()
-	err error
-
-	// Mutex protects against data race conditions.
-	mutex sync.Mutex
-
-	elapseTime time.Duration
-
-	calTime bool
-
-	// WaitGroup allows to block until all callbacks are executed.
-	wg sync.WaitGroup
+	// embargo backs Pipe; see pipeline.go. It is created lazily since most
+	// promises are never piped.
+	embargoOnce sync.Once
+	embargo     *pipeEmbargo
 }
 
 type Timeout struct {
@@ -73,7 +55,9 @@ func (this *Interval) IsClose() bool {
 
 func (this *Interval) Close() {
 	go func() {
-		this.closeChan <- struct{}{}
+		if this.closeChan != nil {
+			this.closeChan <- struct{}{}
+		}
 	}()
 }
 
@@ -95,6 +79,29 @@ func (this *Timeout) execute(duration time.Duration, f func()) {
 	}()
 }
 
+func (this *Timeout) executeCtx(ctx context.Context, duration time.Duration, f func()) {
+	this.closeChan = make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-this.closeChan:
+				return
+			case <-ctx.Done():
+				this.isClose = true
+				close(this.closeChan)
+				this.closeChan = nil
+				return
+			case <-time.After(duration):
+				f()
+				this.isClose = true
+				close(this.closeChan)
+				this.closeChan = nil
+				return
+			}
+		}
+	}()
+}
+
 func SetTimeout(duration time.Duration, f func()) *Timeout {
 	ret := &Timeout{
 		isClose: true,
@@ -103,6 +110,16 @@ func SetTimeout(duration time.Duration, f func()) *Timeout {
 	return ret
 }
 
+// SetTimeoutCtx is like SetTimeout, but cancels itself - without calling f -
+// once ctx is done.
+func SetTimeoutCtx(ctx context.Context, duration time.Duration, f func()) *Timeout {
+	ret := &Timeout{
+		isClose: true,
+	}
+	ret.executeCtx(ctx, duration, f)
+	return ret
+}
+
 func SetInterval(duration time.Duration, f func()) *Interval {
 	ret := &Interval{
 		interval:  duration,
@@ -124,46 +141,53 @@ func SetInterval(duration time.Duration, f func()) *Interval {
 	return ret
 }
 
+// SetIntervalCtx is like SetInterval, but stops ticking once ctx is done.
+func SetIntervalCtx(ctx context.Context, duration time.Duration, f func()) *Interval {
+	ret := &Interval{
+		interval:  duration,
+		ticker:    time.NewTicker(duration),
+		closeChan: make(chan struct{}),
+		f:         f,
+	}
+	go func() {
+		for {
+			select {
+			case <-ret.ticker.C:
+				f()
+			case <-ctx.Done():
+				ret.ticker.Stop()
+				close(ret.closeChan)
+				ret.closeChan = nil
+				return
+			case <-ret.closeChan:
+				ret.ticker.Stop()
+				return
+			}
+		}
+	}()
+	return ret
+}
+
 func Await(p *Promise) (interface{}, error) {
 	return p.Await()
-
 }
 
 func (promise *Promise) CalTime() *Promise {
-	promise.calTime = true
+	promise.inner.CalTime()
 	return promise
 }
 
 func (promise *Promise) Elapse() time.Duration {
-	return promise.elapseTime
+	return promise.inner.Elapse()
 }
 
 // Async instantiates and returns a pointer to a new Promise.
 func Async(executor func(resolve func(interface{}), reject func(interface{}))) *Promise {
-	var promise = &Promise{
-		pending:  true,
-		executor: executor,
-		result:   nil,
-		err:      nil,
-		mutex:    sync.Mutex{},
-		wg:       sync.WaitGroup{},
-	}
-
-	promise.wg.Add(1)
-
-	go func() {
-		defer promise.handlePanic()
-		promise.executor(promise.Resolve, promise.Reject)
-	}()
-
-	return promise
+	return &Promise{inner: AsyncG(executor)}
 }
 
 func (promise *Promise) Resolve(resolution interface{}) {
-	promise.mutex.Lock()
-
-	if !promise.pending {
-		promise.mutex.Unlock()
+	if !promise.inner.isPending() {
 		return
 	}
 
@@ -171,113 +195,150 @@ func (promise *Promise) Resolve(resolution interface{}) {
 	case *Promise:
 		flattenedResult, err := result.Await()
 		if err != nil {
-			promise.mutex.Unlock()
 			promise.Reject(err)
 			return
 		}
-		promise.result = flattenedResult
+		promise.inner.Resolve(flattenedResult)
 	default:
-		promise.result = result
+		promise.inner.Resolve(result)
 	}
-	promise.pending = false
-
-	promise.wg.Done()
-	promise.mutex.Unlock()
 }
 
 func (promise *Promise) Reject(err interface{}) {
-	promise.mutex.Lock()
-	defer promise.mutex.Unlock()
+	promise.inner.Reject(err)
+}
 
-	if !promise.pending {
-		return
-	}
-	if err1, ok := err.(error); ok {
-		promise.err = err1
-	} else {
-		promise.err = errors.New(err.(string))
-	}
-	promise.pending = false
+// Cancel transitions a pending promise to a canceled state; see
+// TypedPromise[T].Cancel for the full semantics.
+func (promise *Promise) Cancel() {
+	promise.inner.Cancel()
+}
 
-	promise.wg.Done()
+// IsCanceled reports whether the promise was transitioned to the canceled
+// state via Cancel, as opposed to an ordinary rejection.
+func (promise *Promise) IsCanceled() bool {
+	return promise.inner.IsCanceled()
 }
 
-func (promise *Promise) handlePanic() {
-	var r = recover()
-	if r != nil {
-		if err, ok := r.(error); ok {
-			promise.Reject(errors.New(err.Error()))
-		} else {
-			promise.Reject(errors.New(r.(string)))
-		}
-	}
+// AsyncCtx is like Async, but the executor additionally receives ctx, and
+// the returned promise is rejected with ctx.Err() if ctx is done before the
+// executor settles it itself.
+func AsyncCtx(ctx context.Context, executor func(ctx context.Context, resolve func(interface{}), reject func(interface{}))) *Promise {
+	return &Promise{inner: AsyncCtxG(ctx, executor)}
+}
+
+// AwaitCtx waits for p to settle, or for ctx to be done, whichever comes
+// first. A canceled ctx only preempts this particular wait - it does not
+// Cancel p itself.
+func AwaitCtx(ctx context.Context, p *Promise) (interface{}, error) {
+	return AwaitCtxG(ctx, p.inner)
 }
 
-// Then appends fulfillment and rejection handlers to the promise,
-// and returns a new promise resolving to the return value of the called handler.
-func (promise *Promise) Then(fulfillment func(data interface{}) interface{}) *Promise {
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		result, err := promise.Await()
+// flattenThen lets a fulfillment handler reject the chain outright (by
+// returning a non-nil error) or hand back a *Promise to flatten into the
+// chain, matching Promises/A+ semantics for the fulfillment case.
+func flattenThen(fulfillment func(data interface{}) (interface{}, error)) func(data interface{}) (interface{}, error) {
+	return func(data interface{}) (interface{}, error) {
+		value, err := fulfillment(data)
 		if err != nil {
-			reject(err)
-			return
+			return nil, err
 		}
-		resolve(fulfillment(result))
-	})
+		if nested, ok := value.(*Promise); ok {
+			return nested.Await()
+		}
+		return value, nil
+	}
+}
+
+// Then appends a fulfillment handler to the promise, and returns a new
+// promise resolving to the value fulfillment returns. fulfillment may
+// itself reject the chain by returning a non-nil error, or hand back a
+// *Promise to flatten into the result, matching Promises/A+ semantics.
+func (promise *Promise) Then(fulfillment func(data interface{}) (interface{}, error)) *Promise {
+	return &Promise{inner: Then(promise.inner, flattenThen(fulfillment))}
 }
 
 // Catch Appends a rejection handler to the promise,
 // and returns a new promise resolving to the return value of the handler.
 func (promise *Promise) Catch(rejection func(err error) interface{}) *Promise {
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		result, err := promise.Await()
+	return &Promise{inner: Catch(promise.inner, rejection)}
+}
+
+// ThenOr appends both a fulfillment and a rejection handler in a single
+// call. onRejected only sees a rejection of promise itself: unlike chaining
+// Then(onFulfilled).Catch(onRejected), an error returned by onFulfilled
+// rejects the returned promise directly without passing through onRejected.
+func (promise *Promise) ThenOr(onFulfilled func(data interface{}) (interface{}, error), onRejected func(err error) interface{}) *Promise {
+	fulfill := flattenThen(onFulfilled)
+	return &Promise{inner: AsyncG(func(resolve func(interface{}), reject func(interface{})) {
+		value, err := promise.Await()
 		if err != nil {
-			reject(rejection(err))
+			reject(onRejected(err))
+			return
+		}
+		result, ferr := fulfill(value)
+		if ferr != nil {
+			reject(ferr)
 			return
 		}
 		resolve(result)
-	})
+	})}
+}
+
+// Finally appends a handler that runs once promise settles, regardless of
+// whether it fulfilled or rejected, purely for its side effect. It returns
+// a new promise that settles to promise's own value or error, unchanged.
+func (promise *Promise) Finally(finalizer func()) *Promise {
+	return &Promise{inner: AsyncG(func(resolve func(interface{}), reject func(interface{})) {
+		value, err := promise.Await()
+		finalizer()
+		if err != nil {
+			reject(err)
+			return
+		}
+		resolve(value)
+	})}
 }
 
 // Await is a blocking function that waits for all callbacks to be executed.
 // Returns value and error.
 // Call on an already resolved Promise to get its result and error
 func (promise *Promise) Await() (interface{}, error) {
-	if promise.calTime {
-		start:=time.Now()
-		promise.wg.Wait()
-		promise.elapseTime = time.Now().Sub(start)
-		return promise.result, promise.err
-	}
-	promise.wg.Wait()
-	return promise.result, promise.err
+	return promise.inner.Await()
 }
 
 func (promise *Promise) AsCallback(f func(interface{}, error)) {
 	go func() {
-		promise.wg.Wait()
-		f(promise.result, promise.err)
+		result, err := promise.inner.Await()
+		f(result, err)
 	}()
 }
 
-type resolutionHelper struct {
-	index int
-	data  interface{}
+// boxSlice converts a TypedPromise[T] into a TypedPromise[interface{}] by boxing its
+// eventual value, so the generic combinators below can back the untyped
+// Each/All and their Ctx variants.
+func boxSlice[T any](p *TypedPromise[T]) *TypedPromise[interface{}] {
+	return Then(p, func(v T) (interface{}, error) {
+		return v, nil
+	})
+}
+
+func unwrap(promises []*Promise) []*TypedPromise[interface{}] {
+	inners := make([]*TypedPromise[interface{}], len(promises))
+	for i, p := range promises {
+		inners[i] = p.inner
+	}
+	return inners
 }
 
 func Each(promises ...*Promise) *Promise {
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		resolutions := make([]interface{}, 0)
-		for _, promise := range promises {
-			result, err := promise.Await()
-			if err != nil {
-				reject(err)
-				return
-			}
-			resolutions = append(resolutions, result)
-		}
-		resolve(resolutions)
-	})
+	return &Promise{inner: boxSlice(EachG(unwrap(promises)...))}
+}
+
+// EachCtx is like Each, but rejects with ctx.Err() if ctx is done before
+// every promise has resolved.
+func EachCtx(ctx context.Context, promises ...*Promise) *Promise {
+	return &Promise{inner: boxSlice(EachCtxG(ctx, unwrap(promises)...))}
 }
 
 // All waits for all promises to be resolved, or for any to be rejected.
@@ -285,72 +346,46 @@ func Each(promises ...*Promise) *Promise {
 // from the resolved promises in the same order as defined in the iterable of multiple promises.
 // If it rejects, it is rejected with the reason from the first promise in the iterable that was rejected.
 func All(promises ...*Promise) *Promise {
-	psLen := len(promises)
-	if psLen == 0 {
-		return Resolve(make([]interface{}, 0))
-	}
-
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		resolutionsChan := make(chan resolutionHelper, psLen)
-		errorChan := make(chan error, psLen)
-
-		for index, promise := range promises {
-			func(i int) {
-				promise.Then(func(data interface{}) interface{} {
-					resolutionsChan <- resolutionHelper{i, data}
-					return data
-				}).Catch(func(err error) interface{} {
-					errorChan <- err
-					return err
-				})
-			}(index)
-		}
-
-		resolutions := make([]interface{}, psLen)
-		for x := 0; x < psLen; x++ {
-			select {
-			case resolution := <-resolutionsChan:
-				resolutions[resolution.index] = resolution.data
+	return &Promise{inner: boxSlice(AllG(unwrap(promises)...))}
+}
 
-			case err := <-errorChan:
-				reject(err)
-				return
-			}
-		}
-		resolve(resolutions)
-	})
+// AllCtx is like All, but rejects with ctx.Err() if ctx is done before
+// every promise has settled. The promises still pending are left untouched
+// - they belong to the caller, and other holders may still be waiting on
+// them.
+func AllCtx(ctx context.Context, promises ...*Promise) *Promise {
+	return &Promise{inner: boxSlice(AllCtxG(ctx, unwrap(promises)...))}
 }
 
 // Race waits until any of the promises is resolved or rejected.
 // If the returned promise resolves, it is resolved with the value of the first promise in the iterable
 // that resolved. If it rejects, it is rejected with the reason from the first promise that was rejected.
 func Race(promises ...*Promise) *Promise {
-	psLen := len(promises)
-	if psLen == 0 {
-		return Resolve(nil)
-	}
-
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		resolutionsChan := make(chan interface{}, psLen)
-		errorChan := make(chan error, psLen)
-
-		for _, promise := range promises {
-			promise.Then(func(data interface{}) interface{} {
-				resolutionsChan <- data
-				return data
-			}).Catch(func(err error) interface{} {
-				errorChan <- err
-				return err
-			})
-		}
+	return &Promise{inner: RaceG(unwrap(promises)...)}
+}
 
-		select {
-		case resolution := <-resolutionsChan:
-			resolve(resolution)
+// RaceCtx is like Race, but rejects with ctx.Err() if ctx is done before
+// any promise has settled. The promises still pending are left untouched
+// - they belong to the caller, and other holders may still be waiting on
+// them.
+func RaceCtx(ctx context.Context, promises ...*Promise) *Promise {
+	return &Promise{inner: RaceCtxG(ctx, unwrap(promises)...)}
+}
 
-		case err := <-errorChan:
-			reject(err)
+// flattenSettlements boxes a TypedPromise[[]Settlement[interface{}]] into the
+// untyped []interface{} shape AllSettled has always returned: each element
+// is either the fulfillment value or the rejection reason.
+func flattenSettlements(settled *TypedPromise[[]Settlement[interface{}]]) *TypedPromise[interface{}] {
+	return Then(settled, func(results []Settlement[interface{}]) (interface{}, error) {
+		out := make([]interface{}, len(results))
+		for i, s := range results {
+			if s.Fulfilled {
+				out[i] = s.Value
+			} else {
+				out[i] = s.Err
+			}
 		}
+		return out, nil
 	})
 }
 
@@ -358,45 +393,29 @@ func Race(promises ...*Promise) *Promise {
 // Returns a promise that resolves after all of the given promises have either resolved or rejected,
 // with an array of objects that each describe the outcome of each promise.
 func AllSettled(promises ...*Promise) *Promise {
-	psLen := len(promises)
-	if psLen == 0 {
+	if len(promises) == 0 {
 		return Resolve(nil)
 	}
 
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		resolutionsChan := make(chan resolutionHelper, psLen)
-
-		for index, promise := range promises {
-			func(i int) {
-				promise.Then(func(data interface{}) interface{} {
-					resolutionsChan <- resolutionHelper{i, data}
-					return data
-				}).Catch(func(err error) interface{} {
-					resolutionsChan <- resolutionHelper{i, err}
-					return err
-				})
-			}(index)
-		}
+	return &Promise{inner: flattenSettlements(AllSettledG(unwrap(promises)...))}
+}
 
-		resolutions := make([]interface{}, psLen)
-		for x := 0; x < psLen; x++ {
-			resolution := <-resolutionsChan
-			resolutions[resolution.index] = resolution.data
-		}
-		resolve(resolutions)
-	})
+// AllSettledCtx is like AllSettled, but rejects with ctx.Err() if ctx is
+// done before every promise has settled.
+func AllSettledCtx(ctx context.Context, promises ...*Promise) *Promise {
+	if len(promises) == 0 {
+		return Resolve(nil)
+	}
+
+	return &Promise{inner: flattenSettlements(AllSettledCtxG(ctx, unwrap(promises)...))}
 }
 
 // Resolve returns a Promise that has been resolved with a given value.
 func Resolve(resolution interface{}) *Promise {
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		resolve(resolution)
-	})
+	return &Promise{inner: ResolveG[interface{}](resolution)}
 }
 
 // Reject returns a Promise that has been rejected with a given error.
 func Reject(err error) *Promise {
-	return Async(func(resolve func(interface{}), reject func(interface{})) {
-		reject(err)
-	})
+	return &Promise{inner: RejectG[interface{}](err)}
 }
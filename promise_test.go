@@ -0,0 +1,325 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPromiseResolve(t *testing.T) {
+	p := Async(func(resolve func(interface{}), reject func(interface{})) {
+		resolve(1)
+	})
+
+	result, err := p.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("got %v, %v, want 1, nil", result, err)
+	}
+}
+
+func TestPromiseResolveFlattensNestedPromise(t *testing.T) {
+	p, _ := NewPromise()
+	p.Resolve(Resolve(2))
+
+	result, err := p.Await()
+	if err != nil || result != 2 {
+		t.Fatalf("got %v, %v, want 2, nil", result, err)
+	}
+}
+
+func TestPromiseThenChains(t *testing.T) {
+	p := Resolve(1)
+	doubled := p.Then(func(data interface{}) (interface{}, error) {
+		return data.(int) * 2, nil
+	})
+
+	result, err := doubled.Await()
+	if err != nil || result != 2 {
+		t.Fatalf("got %v, %v, want 2, nil", result, err)
+	}
+}
+
+func TestPromiseThenFlattensReturnedPromise(t *testing.T) {
+	p := Resolve(1)
+	chained := p.Then(func(data interface{}) (interface{}, error) {
+		return Resolve(data.(int) + 1), nil
+	})
+
+	result, err := chained.Await()
+	if err != nil || result != 2 {
+		t.Fatalf("got %v, %v, want 2, nil", result, err)
+	}
+}
+
+func TestPromiseThenCanReject(t *testing.T) {
+	want := errors.New("rejected by handler")
+	p := Resolve(1)
+	chained := p.Then(func(data interface{}) (interface{}, error) {
+		return nil, want
+	})
+
+	if _, err := chained.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPromiseCatchTransformsRejection(t *testing.T) {
+	want := errors.New("translated")
+	p := Reject(errors.New("original"))
+	caught := p.Catch(func(err error) interface{} {
+		return want
+	})
+
+	if _, err := caught.Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPromiseThenOrFulfilled(t *testing.T) {
+	p := Resolve(1)
+	result, err := p.ThenOr(func(data interface{}) (interface{}, error) {
+		return data.(int) + 1, nil
+	}, func(err error) interface{} {
+		t.Fatal("rejection handler should not run for a fulfilled promise")
+		return err
+	}).Await()
+	if err != nil || result != 2 {
+		t.Fatalf("got %v, %v, want 2, nil", result, err)
+	}
+}
+
+func TestPromiseThenOrRejected(t *testing.T) {
+	want := errors.New("handled")
+	p := Reject(errors.New("original"))
+	_, err := p.ThenOr(func(data interface{}) (interface{}, error) {
+		t.Fatal("fulfillment handler should not run for a rejected promise")
+		return data, nil
+	}, func(err error) interface{} {
+		return want
+	}).Await()
+	if !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPromiseFinallyRunsOnFulfillAndPassesValueThrough(t *testing.T) {
+	var ran bool
+	p := Resolve(5)
+	result, err := p.Finally(func() {
+		ran = true
+	}).Await()
+	if !ran {
+		t.Fatal("finalizer should have run")
+	}
+	if err != nil || result != 5 {
+		t.Fatalf("got %v, %v, want 5, nil", result, err)
+	}
+}
+
+func TestPromiseFinallyRunsOnRejectAndPassesErrorThrough(t *testing.T) {
+	var ran bool
+	want := errors.New("boom")
+	p := Reject(want)
+	_, err := p.Finally(func() {
+		ran = true
+	}).Await()
+	if !ran {
+		t.Fatal("finalizer should have run")
+	}
+	if !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestPromiseCancel(t *testing.T) {
+	p := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(50 * time.Millisecond)
+		resolve(1)
+	})
+	p.Cancel()
+
+	if _, err := p.Await(); !errors.Is(err, ErrCanceled) {
+		t.Fatalf("got %v, want %v", err, ErrCanceled)
+	}
+	if !p.IsCanceled() {
+		t.Fatal("IsCanceled should report true after Cancel")
+	}
+}
+
+func TestEach(t *testing.T) {
+	p1 := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(20 * time.Millisecond)
+		resolve(1)
+	})
+	p2 := Resolve(2)
+
+	result, err := Each(p1, p2).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values := result.([]interface{})
+	if values[0] != 1 || values[1] != 2 {
+		t.Fatalf("got %v, want [1 2]", values)
+	}
+}
+
+func TestAll(t *testing.T) {
+	p1 := Resolve(1)
+	p2 := Resolve(2)
+
+	result, err := All(p1, p2).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values := result.([]interface{})
+	if values[0] != 1 || values[1] != 2 {
+		t.Fatalf("got %v, want [1 2]", values)
+	}
+}
+
+func TestAllRejectsOnFirstError(t *testing.T) {
+	want := errors.New("failed")
+	p1 := Reject(want)
+	p2 := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(20 * time.Millisecond)
+		resolve(2)
+	})
+
+	if _, err := All(p1, p2).Await(); !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", err, want)
+	}
+}
+
+func TestRace(t *testing.T) {
+	fast := Resolve(1)
+	slow := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(50 * time.Millisecond)
+		resolve(2)
+	})
+
+	result, err := Race(fast, slow).Await()
+	if err != nil || result != 1 {
+		t.Fatalf("got %v, %v, want 1, nil", result, err)
+	}
+}
+
+func TestAllSettled(t *testing.T) {
+	ok := Resolve(1)
+	want := errors.New("bad")
+	fail := Reject(want)
+
+	result, err := AllSettled(ok, fail).Await()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values := result.([]interface{})
+	if values[0] != 1 {
+		t.Fatalf("got %v, want 1", values[0])
+	}
+	if err, ok := values[1].(error); !ok || !errors.Is(err, want) {
+		t.Fatalf("got %v, want %v", values[1], want)
+	}
+}
+
+func TestAllSettledEmptyResolvesNil(t *testing.T) {
+	result, err := AllSettled().Await()
+	if err != nil || result != nil {
+		t.Fatalf("got %v, %v, want nil, nil", result, err)
+	}
+}
+
+func TestAwaitCtxTimesOutWithoutCancelingPromise(t *testing.T) {
+	p := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(100 * time.Millisecond)
+		resolve(1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := AwaitCtx(ctx, p); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	result, err := p.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("promise should still settle on its own, got %v, %v", result, err)
+	}
+	if p.IsCanceled() {
+		t.Fatal("AwaitCtx must not Cancel the promise it was waiting on")
+	}
+}
+
+func TestAllCtxRejectsWithoutCancelingInputs(t *testing.T) {
+	slow := Async(func(resolve func(interface{}), reject func(interface{})) {
+		time.Sleep(100 * time.Millisecond)
+		resolve(1)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := AllCtx(ctx, slow).Await(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	result, err := slow.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("input promise should still settle on its own, got %v, %v", result, err)
+	}
+	if slow.IsCanceled() {
+		t.Fatal("AllCtx must not Cancel promises it doesn't own")
+	}
+}
+
+func TestSetTimeoutFires(t *testing.T) {
+	done := make(chan struct{})
+	SetTimeout(10*time.Millisecond, func() {
+		close(done)
+	})
+
+	select {
+	case <-done:
+	case <-time.After(200 * time.Millisecond):
+		t.Fatal("timeout callback never fired")
+	}
+}
+
+func TestSetTimeoutCtxCancelSkipsCallback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	called := make(chan struct{}, 1)
+	SetTimeoutCtx(ctx, 50*time.Millisecond, func() {
+		called <- struct{}{}
+	})
+	cancel()
+
+	select {
+	case <-called:
+		t.Fatal("callback should not run once ctx is canceled first")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestSetIntervalCtxStopsTicking(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var ticks int32
+	iv := SetIntervalCtx(ctx, 10*time.Millisecond, func() {
+		atomic.AddInt32(&ticks, 1)
+	})
+	time.Sleep(35 * time.Millisecond)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+
+	seen := atomic.LoadInt32(&ticks)
+	time.Sleep(50 * time.Millisecond)
+	if got := atomic.LoadInt32(&ticks); got != seen {
+		t.Fatalf("ticks kept increasing after ctx was canceled: %d -> %d", seen, got)
+	}
+	if !iv.IsClose() {
+		t.Fatal("IsClose should report true once ctx has fired")
+	}
+
+	iv.Close() // must not panic even though ctx already closed the interval
+}
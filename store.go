@@ -0,0 +1,148 @@
+package promise
+
+import (
+	"context"
+	"sync"
+)
+
+// EvictionPolicy decides whether a Store entry whose refcount has just
+// dropped to zero should be evicted immediately, or kept around so a
+// future caller for the same key can reuse its already-settled result
+// instead of recomputing it.
+type EvictionPolicy interface {
+	// ShouldEvict is called with the entry's refcount right after a
+	// Release drops it to zero. A policy that always returns true gets the eviction
+	// behavior of ImmediateEviction.
+	ShouldEvict(refs int) bool
+}
+
+// ImmediateEviction evicts an entry as soon as its last handle is
+// released. It is the default policy used by NewStore.
+type ImmediateEviction struct{}
+
+// ShouldEvict implements EvictionPolicy.
+func (ImmediateEviction) ShouldEvict(refs int) bool {
+	return refs <= 0
+}
+
+type storeEntry[T any] struct {
+	refs    int
+	promise *TypedPromise[T]
+}
+
+// Store memoizes expensive async work keyed by K: concurrent callers for
+// the same key share one execution of fn and one underlying TypedPromise[T],
+// modeled after golang.org/x/tools' memoize package. Each call to Promise
+// returns a Handle that must be explicitly Released; once a key's refcount
+// reaches zero, the Store's EvictionPolicy decides whether the entry is
+// evicted.
+type Store[K comparable, T any] struct {
+	mu      sync.Mutex
+	entries map[K]*storeEntry[T]
+	evict   EvictionPolicy
+}
+
+// NewStore returns an empty Store. A nil evict defaults to
+// ImmediateEviction.
+func NewStore[K comparable, T any](evict EvictionPolicy) *Store[K, T] {
+	if evict == nil {
+		evict = ImmediateEviction{}
+	}
+	return &Store[K, T]{
+		entries: make(map[K]*storeEntry[T]),
+		evict:   evict,
+	}
+}
+
+// Handle is a live reference to a promise memoized in a Store. Callers must
+// call Release exactly once when they are done observing the promise; an
+// un-Released handle keeps its entry, and the result it memoizes, in the
+// Store forever.
+type Handle[K comparable, T any] struct {
+	store    *Store[K, T]
+	key      K
+	entry    *storeEntry[T]
+	mu       sync.Mutex
+	released bool
+}
+
+// Promise returns the memoized promise this handle refers to.
+func (h *Handle[K, T]) Promise() *TypedPromise[T] {
+	return h.entry.promise
+}
+
+// Await blocks until the memoized computation settles.
+func (h *Handle[K, T]) Await() (T, error) {
+	return h.entry.promise.Await()
+}
+
+// Get waits for the memoized computation bound to ctx: if ctx is done
+// first, Get returns ctx.Err() without affecting the shared computation -
+// other handles sharing this entry still observe its eventual outcome.
+func (h *Handle[K, T]) Get(ctx context.Context) (T, error) {
+	return AwaitCtxG(ctx, h.entry.promise)
+}
+
+// Release drops this handle's reference to its entry. Once every handle
+// for a key has been released, the Store's EvictionPolicy is consulted and
+// the entry is evicted from the Store if it says to. Release is safe to
+// call more than once; only the first call has an effect.
+func (h *Handle[K, T]) Release() {
+	h.mu.Lock()
+	if h.released {
+		h.mu.Unlock()
+		return
+	}
+	h.released = true
+	h.mu.Unlock()
+
+	h.store.release(h.key, h.entry)
+}
+
+func (s *Store[K, T]) release(key K, entry *storeEntry[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.refs--
+	if current, ok := s.entries[key]; !ok || current != entry {
+		// Already superseded by a fresher entry for this key.
+		return
+	}
+	if entry.refs <= 0 && s.evict.ShouldEvict(entry.refs) {
+		delete(s.entries, key)
+	}
+}
+
+// Promise returns a Handle to the promise memoized under key, invoking fn
+// at most once per live key even under heavy concurrency: if an entry for
+// key is already live, its refcount is incremented and its existing
+// promise is returned instead of calling fn again.
+func (s *Store[K, T]) Promise(key K, fn func() (T, error)) *Handle[K, T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &storeEntry[T]{
+			promise: AsyncG(func(resolve func(T), reject func(interface{})) {
+				result, err := fn()
+				if err != nil {
+					reject(err)
+					return
+				}
+				resolve(result)
+			}),
+		}
+		s.entries[key] = entry
+	}
+	entry.refs++
+
+	return &Handle[K, T]{store: s, key: key, entry: entry}
+}
+
+// Len reports the number of keys currently live in the Store.
+func (s *Store[K, T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
@@ -0,0 +1,173 @@
+package promise
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStorePromiseDedupsConcurrentCallers(t *testing.T) {
+	s := NewStore[string, int](nil)
+
+	var calls int32
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	handles := make([]*Handle[string, int], 10)
+	for i := range handles {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			handles[i] = s.Promise("key", fn)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, h := range handles {
+		result, err := h.Await()
+		if err != nil || result != 42 {
+			t.Fatalf("got %d, %v, want 42, nil", result, err)
+		}
+	}
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("fn called %d times, want exactly 1", got)
+	}
+
+	for _, h := range handles {
+		h.Release()
+	}
+}
+
+func TestStoreImmediateEvictionRecomputesAfterRelease(t *testing.T) {
+	s := NewStore[string, int](nil)
+
+	var calls int32
+	fn := func() (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		return int(n), nil
+	}
+
+	h1 := s.Promise("key", fn)
+	first, _ := h1.Await()
+	h1.Release()
+
+	if s.Len() != 0 {
+		t.Fatalf("entry should be evicted once its last handle is released, Len()=%d", s.Len())
+	}
+
+	h2 := s.Promise("key", fn)
+	second, _ := h2.Await()
+	h2.Release()
+
+	if first == second {
+		t.Fatalf("fn should run again after the entry was evicted, got %d twice", first)
+	}
+}
+
+func TestStoreSharesEntryWhileHandleLive(t *testing.T) {
+	s := NewStore[string, int](nil)
+
+	var calls int32
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 1, nil
+	}
+
+	h1 := s.Promise("key", fn)
+	h2 := s.Promise("key", fn)
+	h1.Await()
+
+	if s.Len() != 1 {
+		t.Fatalf("Len()=%d, want 1 while both handles are live", s.Len())
+	}
+
+	h1.Release()
+	if s.Len() != 1 {
+		t.Fatalf("entry should survive while h2 still holds it, Len()=%d", s.Len())
+	}
+
+	h2.Release()
+	if s.Len() != 0 {
+		t.Fatalf("entry should be evicted once every handle is released, Len()=%d", s.Len())
+	}
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("fn called %d times, want exactly 1", calls)
+	}
+}
+
+type keepAliveEviction struct{}
+
+func (keepAliveEviction) ShouldEvict(refs int) bool {
+	return false
+}
+
+func TestStoreCustomEvictionPolicyKeepsEntry(t *testing.T) {
+	s := NewStore[string, int](keepAliveEviction{})
+
+	var calls int32
+	fn := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 1, nil
+	}
+
+	h := s.Promise("key", fn)
+	h.Await()
+	h.Release()
+
+	if s.Len() != 1 {
+		t.Fatalf("keepAliveEviction should keep the entry around, Len()=%d", s.Len())
+	}
+
+	s.Promise("key", fn).Release()
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("fn called %d times, want exactly 1 since the entry was kept", calls)
+	}
+}
+
+func TestHandleReleaseIsIdempotent(t *testing.T) {
+	s := NewStore[string, int](nil)
+	h := s.Promise("key", func() (int, error) { return 1, nil })
+	h.Await()
+
+	h.Release()
+	h.Release()
+
+	if s.Len() != 0 {
+		t.Fatalf("Len()=%d, want 0", s.Len())
+	}
+}
+
+func TestHandleGetRespectsCtxWithoutAffectingOtherHandles(t *testing.T) {
+	s := NewStore[string, int](nil)
+	fn := func() (int, error) {
+		time.Sleep(100 * time.Millisecond)
+		return 1, nil
+	}
+
+	h1 := s.Promise("key", fn)
+	h2 := s.Promise("key", fn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := h1.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	result, err := h2.Await()
+	if err != nil || result != 1 {
+		t.Fatalf("other handle should still observe the shared computation's outcome, got %d, %v", result, err)
+	}
+
+	h1.Release()
+	h2.Release()
+}